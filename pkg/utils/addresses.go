@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/vishvananda/netlink"
+)
+
+// AddressFilter is a predicate used to decide whether a netlink address is a
+// usable candidate when resolving VIPs or default routes.
+type AddressFilter func(netlink.Addr) bool
+
+// RouteFilter is a predicate used to decide whether a netlink route should be
+// considered when looking for a default route or a route to a VIP.
+type RouteFilter func(netlink.Route) bool
+
+// ValidNodeAddress excludes loopback, link-local and deprecated addresses,
+// leaving only addresses that are safe to advertise as a node address.
+func ValidNodeAddress(addr netlink.Addr) bool {
+	if addr.IP.IsLoopback() || addr.IP.IsLinkLocalUnicast() || addr.IP.IsLinkLocalMulticast() {
+		return false
+	}
+	if addr.PreferedLft == 0 {
+		return false
+	}
+	return true
+}
+
+type addrMapFunc func(AddressFilter) (map[netlink.Link][]netlink.Addr, error)
+type routeMapFunc func(RouteFilter) (map[int][]netlink.Route, error)
+
+// AddressesRouting returns, for each of the given VIPs, the node addresses
+// (one per family, VIP's family first) on the interface that the kernel
+// would use to route traffic to that VIP.
+func AddressesRouting(vips []net.IP, af AddressFilter) ([]net.IP, error) {
+	return addressesRoutingInternal(vips, af, getAddrMap, getRouteMap)
+}
+
+// AddressesDefault returns the node addresses (one per family, IPv4 first)
+// on the interface holding the default route.
+func AddressesDefault(af AddressFilter) ([]net.IP, error) {
+	return selectDefaultAddressFactory()(af)
+}
+
+func addressesRoutingInternal(vips []net.IP, af AddressFilter, getAddrs addrMapFunc, getRoutes routeMapFunc) ([]net.IP, error) {
+	addrMap, err := getAddrs(af)
+	if err != nil {
+		return nil, err
+	}
+	routeMap, err := getRoutes(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []net.IP
+	for _, vip := range vips {
+		link, err := linkRoutingTo(vip, addrMap, routeMap)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, addressesForLink(addrMap[link], vip.To4() != nil)...)
+	}
+	return result, nil
+}
+
+func addressesDefaultInternal(af AddressFilter, getAddrs addrMapFunc, getRoutes routeMapFunc) ([]net.IP, error) {
+	addrMap, err := getAddrs(af)
+	if err != nil {
+		return nil, err
+	}
+	routeMap, err := getRoutes(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := linkWithDefaultRoute(addrMap, routeMap)
+	if err != nil {
+		return nil, err
+	}
+	return addressesForLink(addrMap[link], true), nil
+}
+
+// linkRoutingTo returns the link that the kernel would use to route traffic
+// to ip, based on the most specific matching route in routeMap.
+func linkRoutingTo(ip net.IP, addrMap map[netlink.Link][]netlink.Addr, routeMap map[int][]netlink.Route) (netlink.Link, error) {
+	var best netlink.Link
+	bestPrefix := -1
+	for _, link := range sortedLinks(addrMap) {
+		for _, route := range routeMap[link.Attrs().Index] {
+			if route.Dst == nil || !route.Dst.Contains(ip) {
+				continue
+			}
+			prefix, _ := route.Dst.Mask.Size()
+			if prefix > bestPrefix {
+				best = link
+				bestPrefix = prefix
+			}
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("failed to find a link routing to %s", ip)
+	}
+	return best, nil
+}
+
+// linkWithDefaultRoute returns the link carrying a default (0.0.0.0/0 or
+// ::/0) route.
+func linkWithDefaultRoute(addrMap map[netlink.Link][]netlink.Addr, routeMap map[int][]netlink.Route) (netlink.Link, error) {
+	for _, link := range sortedLinks(addrMap) {
+		for _, route := range routeMap[link.Attrs().Index] {
+			if route.Dst == nil {
+				return link, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("failed to find a link with a default route")
+}
+
+// addressesForLink returns the first valid address of each family present on
+// addrs, ordered with the preferred family (v4 when preferV4 is true) first.
+func addressesForLink(addrs []netlink.Addr, preferV4 bool) []net.IP {
+	var v4, v6 net.IP
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			if v4 == nil {
+				v4 = addr.IP
+			}
+		} else if v6 == nil {
+			v6 = addr.IP
+		}
+	}
+
+	var result []net.IP
+	if preferV4 {
+		if v4 != nil {
+			result = append(result, v4)
+		}
+		if v6 != nil {
+			result = append(result, v6)
+		}
+	} else {
+		if v6 != nil {
+			result = append(result, v6)
+		}
+		if v4 != nil {
+			result = append(result, v4)
+		}
+	}
+	return result
+}
+
+func sortedLinks(addrMap map[netlink.Link][]netlink.Addr) []netlink.Link {
+	links := make([]netlink.Link, 0, len(addrMap))
+	for link := range addrMap {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Attrs().Index < links[j].Attrs().Index
+	})
+	return links
+}
+
+func getAddrMap(af AddressFilter) (map[netlink.Link][]netlink.Addr, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	addrMap := make(map[netlink.Link][]netlink.Addr)
+	for _, link := range links {
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses for %s: %w", link.Attrs().Name, err)
+		}
+		for _, addr := range addrs {
+			if af != nil && !af(addr) {
+				continue
+			}
+			addrMap[link] = append(addrMap[link], addr)
+		}
+	}
+	return addrMap, nil
+}
+
+func getRouteMap(rf RouteFilter) (map[int][]netlink.Route, error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	routeMap := make(map[int][]netlink.Route)
+	for _, route := range routes {
+		if rf != nil && !rf(route) {
+			continue
+		}
+		routeMap[route.LinkIndex] = append(routeMap[route.LinkIndex], route)
+	}
+	return routeMap, nil
+}
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+
+	"github.com/vishvananda/netlink"
+)
+
+// AddressFilterAddr is the net/netip counterpart to AddressFilter. Unlike
+// AddressFilter it has no visibility into netlink-specific metadata such as
+// preferred lifetime, so ValidNodeAddrPort cannot exclude deprecated
+// addresses the way ValidNodeAddress does.
+type AddressFilterAddr func(netip.Prefix) bool
+
+// RouteFilterAddr is the net/netip counterpart to RouteFilter.
+type RouteFilterAddr func(RouteAddr) bool
+
+// RouteAddr is a netip-based route. A zero-value (invalid) Dst represents a
+// default route, mirroring the nil *net.IPNet convention netlink.Route uses.
+type RouteAddr struct {
+	Dst       netip.Prefix
+	LinkIndex int
+}
+
+// ValidNodeAddrPort excludes loopback and link-local addresses, the
+// netip-based equivalent of ValidNodeAddress. Because it cannot see preferred
+// lifetime, it does NOT exclude deprecated addresses: on an interface using
+// IPv6 temporary/privacy addressing, AddressesRoutingAddr/AddressesDefaultAddr
+// may return a deprecated temporary address that AddressesRouting/
+// AddressesDefault (using ValidNodeAddress) would have skipped. Callers that
+// need deprecation-aware selection on such interfaces should use the
+// netlink.Addr-based API instead.
+func ValidNodeAddrPort(p netip.Prefix) bool {
+	addr := p.Addr()
+	if addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() {
+		return false
+	}
+	return true
+}
+
+type addrMapAddrFunc func(AddressFilterAddr) (map[int][]netip.Prefix, error)
+type routeMapAddrFunc func(RouteFilterAddr) (map[int][]RouteAddr, error)
+
+// AddressesRoutingAddr is the net/netip counterpart to AddressesRouting.
+func AddressesRoutingAddr(vips []netip.Addr, af AddressFilterAddr) ([]netip.Addr, error) {
+	return addressesRoutingAddrInternal(vips, af, getAddrMapAddr, getRouteMapAddr)
+}
+
+// AddressesDefaultAddr is the net/netip counterpart to AddressesDefault.
+func AddressesDefaultAddr(af AddressFilterAddr) ([]netip.Addr, error) {
+	return addressesDefaultAddrInternal(af, getAddrMapAddr, getRouteMapAddr)
+}
+
+func addressesRoutingAddrInternal(vips []netip.Addr, af AddressFilterAddr, getAddrs addrMapAddrFunc, getRoutes routeMapAddrFunc) ([]netip.Addr, error) {
+	addrMap, err := getAddrs(af)
+	if err != nil {
+		return nil, err
+	}
+	routeMap, err := getRoutes(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []netip.Addr
+	for _, vip := range vips {
+		link, err := linkRoutingToAddr(vip, addrMap, routeMap)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, addressesForLinkAddr(addrMap[link], vip.Is4())...)
+	}
+	return result, nil
+}
+
+func addressesDefaultAddrInternal(af AddressFilterAddr, getAddrs addrMapAddrFunc, getRoutes routeMapAddrFunc) ([]netip.Addr, error) {
+	addrMap, err := getAddrs(af)
+	if err != nil {
+		return nil, err
+	}
+	routeMap, err := getRoutes(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := linkWithDefaultRouteAddr(addrMap, routeMap)
+	if err != nil {
+		return nil, err
+	}
+	return addressesForLinkAddr(addrMap[link], true), nil
+}
+
+func linkRoutingToAddr(ip netip.Addr, addrMap map[int][]netip.Prefix, routeMap map[int][]RouteAddr) (int, error) {
+	best := -1
+	bestPrefix := -1
+	for _, idx := range sortedLinkIndexes(addrMap) {
+		for _, route := range routeMap[idx] {
+			if !route.Dst.IsValid() || !route.Dst.Contains(ip) {
+				continue
+			}
+			if bits := route.Dst.Bits(); bits > bestPrefix {
+				best = idx
+				bestPrefix = bits
+			}
+		}
+	}
+	if best == -1 {
+		return 0, fmt.Errorf("failed to find a link routing to %s", ip)
+	}
+	return best, nil
+}
+
+func linkWithDefaultRouteAddr(addrMap map[int][]netip.Prefix, routeMap map[int][]RouteAddr) (int, error) {
+	for _, idx := range sortedLinkIndexes(addrMap) {
+		for _, route := range routeMap[idx] {
+			if !route.Dst.IsValid() {
+				return idx, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("failed to find a link with a default route")
+}
+
+func addressesForLinkAddr(prefixes []netip.Prefix, preferV4 bool) []netip.Addr {
+	var v4, v6 netip.Addr
+	for _, p := range prefixes {
+		addr := p.Addr()
+		if addr.Is4() {
+			if !v4.IsValid() {
+				v4 = addr
+			}
+		} else if !v6.IsValid() {
+			v6 = addr
+		}
+	}
+
+	var result []netip.Addr
+	if preferV4 {
+		if v4.IsValid() {
+			result = append(result, v4)
+		}
+		if v6.IsValid() {
+			result = append(result, v6)
+		}
+	} else {
+		if v6.IsValid() {
+			result = append(result, v6)
+		}
+		if v4.IsValid() {
+			result = append(result, v4)
+		}
+	}
+	return result
+}
+
+func sortedLinkIndexes(addrMap map[int][]netip.Prefix) []int {
+	indexes := make([]int, 0, len(addrMap))
+	for idx := range addrMap {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+func getAddrMapAddr(af AddressFilterAddr) (map[int][]netip.Prefix, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	addrMap := make(map[int][]netip.Prefix)
+	for _, link := range links {
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses for %s: %w", link.Attrs().Name, err)
+		}
+		idx := link.Attrs().Index
+		for _, addr := range addrs {
+			prefix, ok := prefixFromIPNet(addr.IPNet)
+			if !ok {
+				continue
+			}
+			if af != nil && !af(prefix) {
+				continue
+			}
+			addrMap[idx] = append(addrMap[idx], prefix)
+		}
+	}
+	return addrMap, nil
+}
+
+func getRouteMapAddr(rf RouteFilterAddr) (map[int][]RouteAddr, error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	routeMap := make(map[int][]RouteAddr)
+	for _, route := range routes {
+		var dst netip.Prefix
+		if route.Dst != nil {
+			var ok bool
+			dst, ok = prefixFromIPNet(route.Dst)
+			if !ok {
+				continue
+			}
+		}
+		ra := RouteAddr{Dst: dst, LinkIndex: route.LinkIndex}
+		if rf != nil && !rf(ra) {
+			continue
+		}
+		routeMap[route.LinkIndex] = append(routeMap[route.LinkIndex], ra)
+	}
+	return routeMap, nil
+}
+
+func prefixFromIPNet(n *net.IPNet) (netip.Prefix, bool) {
+	addr, ok := netip.AddrFromSlice(n.IP)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	ones, _ := n.Mask.Size()
+	return netip.PrefixFrom(addr, ones), true
+}
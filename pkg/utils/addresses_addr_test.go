@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"net/netip"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	loIdx   = 0
+	eth0Idx = 1
+	eth1Idx = 2
+)
+
+func maybeAddPrefix(addrs map[int][]netip.Prefix, af AddressFilterAddr, linkIdx int, prefixStr string) {
+	prefix := netip.MustParsePrefix(prefixStr)
+	if af != nil && !af(prefix) {
+		return
+	}
+	addrs[linkIdx] = append(addrs[linkIdx], prefix)
+}
+
+func maybeAddRouteAddr(routes map[int][]RouteAddr, rf RouteFilterAddr, linkIdx int, destination string) {
+	var dst netip.Prefix
+	if destination != "" {
+		dst = netip.MustParsePrefix(destination)
+	}
+	ra := RouteAddr{Dst: dst, LinkIndex: linkIdx}
+	if rf != nil && !rf(ra) {
+		return
+	}
+	routes[linkIdx] = append(routes[linkIdx], ra)
+}
+
+func ipv4AddrMapAddr(af AddressFilterAddr) (map[int][]netip.Prefix, error) {
+	addrs := make(map[int][]netip.Prefix)
+	maybeAddPrefix(addrs, af, loIdx, "127.0.0.1/8")
+	maybeAddPrefix(addrs, af, eth0Idx, "10.0.0.5/24")
+	maybeAddPrefix(addrs, af, eth0Idx, "169.254.10.10/16")
+	maybeAddPrefix(addrs, af, eth1Idx, "192.168.1.2/24")
+	return addrs, nil
+}
+
+func ipv4RouteMapAddr(rf RouteFilterAddr) (map[int][]RouteAddr, error) {
+	routes := make(map[int][]RouteAddr)
+	maybeAddRouteAddr(routes, rf, eth0Idx, "")
+	maybeAddRouteAddr(routes, rf, eth0Idx, "10.0.0.0/24")
+	maybeAddRouteAddr(routes, rf, eth1Idx, "192.168.1.0/24")
+	return routes, nil
+}
+
+// ipv6AddrMapAddr mirrors ipv6AddrMap, including eth1's temporary-address
+// ordering, except that netip.Prefix carries no lifetime so the addresses
+// addresses_test.go marks deprecated can't be told apart here.
+func ipv6AddrMapAddr(af AddressFilterAddr) (map[int][]netip.Prefix, error) {
+	addrs := make(map[int][]netip.Prefix)
+	maybeAddPrefix(addrs, af, loIdx, "127.0.0.1/8")
+	maybeAddPrefix(addrs, af, loIdx, "::1/128")
+	maybeAddPrefix(addrs, af, eth0Idx, "fd00::5/64")
+	maybeAddPrefix(addrs, af, eth0Idx, "fe80::1234/64")
+	maybeAddPrefix(addrs, af, eth1Idx, "fd01::3/64")
+	maybeAddPrefix(addrs, af, eth1Idx, "fd01::4/64")
+	maybeAddPrefix(addrs, af, eth1Idx, "fd01::5/64")
+	return addrs, nil
+}
+
+func ipv6RouteMapAddr(rf RouteFilterAddr) (map[int][]RouteAddr, error) {
+	routes := make(map[int][]RouteAddr)
+	maybeAddRouteAddr(routes, rf, eth0Idx, "")
+	maybeAddRouteAddr(routes, rf, eth0Idx, "fd00::/64")
+	maybeAddRouteAddr(routes, rf, eth0Idx, "fd02::/64")
+	maybeAddRouteAddr(routes, rf, eth1Idx, "fd01::/64")
+	return routes, nil
+}
+
+func dualStackAddrMapAddr(af AddressFilterAddr) (map[int][]netip.Prefix, error) {
+	addrs := make(map[int][]netip.Prefix)
+	v4, _ := ipv4AddrMapAddr(af)
+	v6, _ := ipv6AddrMapAddr(af)
+	for idx, prefixes := range v4 {
+		addrs[idx] = append(addrs[idx], prefixes...)
+	}
+	for idx, prefixes := range v6 {
+		addrs[idx] = append(addrs[idx], prefixes...)
+	}
+	return addrs, nil
+}
+
+func dualStackRouteMapAddr(rf RouteFilterAddr) (map[int][]RouteAddr, error) {
+	routes := make(map[int][]RouteAddr)
+	v4, _ := ipv4RouteMapAddr(rf)
+	v6, _ := ipv6RouteMapAddr(rf)
+	for idx, r := range v4 {
+		routes[idx] = append(routes[idx], r...)
+	}
+	for idx, r := range v6 {
+		routes[idx] = append(routes[idx], r...)
+	}
+	return routes, nil
+}
+
+var _ = Describe("addressesRoutingAddrInternal", func() {
+	It("matches an IPv4 VIP on the primary interface", func() {
+		addrs, err := addressesRoutingAddrInternal(
+			[]netip.Addr{netip.MustParseAddr("10.0.0.2")},
+			ValidNodeAddrPort,
+			ipv4AddrMapAddr,
+			ipv4RouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("10.0.0.5")}))
+	})
+
+	It("matches an IPv4 VIP on the secondary interface", func() {
+		addrs, err := addressesRoutingAddrInternal(
+			[]netip.Addr{netip.MustParseAddr("192.168.1.99")},
+			ValidNodeAddrPort,
+			ipv4AddrMapAddr,
+			ipv4RouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("192.168.1.2")}))
+	})
+
+	It("matches an IPv6 VIP on the primary interface", func() {
+		addrs, err := addressesRoutingAddrInternal(
+			[]netip.Addr{netip.MustParseAddr("fd00::2")},
+			ValidNodeAddrPort,
+			ipv6AddrMapAddr,
+			ipv6RouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("fd00::5")}))
+	})
+
+	It("matches an IPv4 VIP on a dual-stack interface", func() {
+		addrs, err := addressesRoutingAddrInternal(
+			[]netip.Addr{netip.MustParseAddr("10.0.0.2")},
+			ValidNodeAddrPort,
+			dualStackAddrMapAddr,
+			dualStackRouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("10.0.0.5"), netip.MustParseAddr("fd00::5")}))
+	})
+
+	It("matches an IPv6 VIP on a dual-stack interface", func() {
+		addrs, err := addressesRoutingAddrInternal(
+			[]netip.Addr{netip.MustParseAddr("fd01::2")},
+			ValidNodeAddrPort,
+			dualStackAddrMapAddr,
+			dualStackRouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("fd01::3"), netip.MustParseAddr("192.168.1.2")}))
+	})
+
+	// Unlike addresses_test.go's "matches an IPv6 VIP on an interface with
+	// temporary IPs" case, ValidNodeAddrPort has no lifetime to check, so it
+	// cannot skip eth1's deprecated fd01::3/fd01::4 the way ValidNodeAddress
+	// does: AddressesRoutingAddr returns the first address in netlink's
+	// list order regardless of deprecation. Callers that need the
+	// lifetime-aware behavior of AddressesRouting must keep using it (or
+	// the netlink.Addr-based AddressFilter) for interfaces running
+	// IPv6 temporary/privacy addressing; AddressesRoutingAddr alone is not a
+	// drop-in replacement there.
+	It("returns a deprecated temporary address instead of skipping it, unlike AddressesRoutingAddr's net.IP counterpart", func() {
+		addrs, err := addressesRoutingAddrInternal(
+			[]netip.Addr{netip.MustParseAddr("fd01::2")},
+			ValidNodeAddrPort,
+			ipv6AddrMapAddr,
+			ipv6RouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("fd01::3")}))
+	})
+})
+
+var _ = Describe("addressesDefaultAddrInternal", func() {
+	It("finds an interface with a default route", func() {
+		addrs, err := addressesDefaultAddrInternal(
+			ValidNodeAddrPort,
+			ipv4AddrMapAddr,
+			ipv4RouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("10.0.0.5")}))
+	})
+
+	It("finds an interface with a default route in an IPv6 cluster", func() {
+		addrs, err := addressesDefaultAddrInternal(
+			ValidNodeAddrPort,
+			ipv6AddrMapAddr,
+			ipv6RouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("fd00::5")}))
+	})
+
+	It("finds an interface with a default route in a dual-stack cluster", func() {
+		addrs, err := addressesDefaultAddrInternal(
+			ValidNodeAddrPort,
+			dualStackAddrMapAddr,
+			dualStackRouteMapAddr,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("10.0.0.5"), netip.MustParseAddr("fd00::5")}))
+	})
+})
+
+var _ = Describe("ValidNodeAddrPort", func() {
+	It("rejects loopback and link-local prefixes", func() {
+		Expect(ValidNodeAddrPort(netip.MustParsePrefix("127.0.0.1/8"))).To(BeFalse())
+		Expect(ValidNodeAddrPort(netip.MustParsePrefix("169.254.10.10/16"))).To(BeFalse())
+		Expect(ValidNodeAddrPort(netip.MustParsePrefix("fe80::1234/64"))).To(BeFalse())
+	})
+
+	It("accepts routable prefixes", func() {
+		Expect(ValidNodeAddrPort(netip.MustParsePrefix("10.0.0.5/24"))).To(BeTrue())
+	})
+})
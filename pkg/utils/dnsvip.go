@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Resolver is the subset of net.Resolver used to look up VIP hostnames. It
+// is satisfied by *net.Resolver and can be swapped out in tests.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ResolveTargetsOptions configures ResolveTargets.
+type ResolveTargetsOptions struct {
+	// Resolver performs the DNS lookups. Defaults to net.DefaultResolver.
+	Resolver Resolver
+	// Interval is how often targets are re-resolved. Defaults to 30s.
+	Interval time.Duration
+	// KeepPrevious, when true, keeps a hostname's previously resolved
+	// addresses in the emitted set when a re-resolution returns a different
+	// (but non-empty) answer, instead of replacing them outright. This
+	// matters for long-lived keepalived/haproxy connections that still
+	// reference a stale address after a DNS change.
+	KeepPrevious bool
+}
+
+// TargetAddresses is emitted on the channel returned by ResolveTargets
+// whenever a hostname's resolved addresses change.
+type TargetAddresses struct {
+	// Host is the hostname this change applies to, so a renderer watching
+	// several targets can tell them apart and map Current/Previous back to
+	// the VIP it came from.
+	Host string
+	// Current are the addresses from the most recent successful resolution
+	// (plus any retained previous addresses when KeepPrevious is set).
+	Current []net.IP
+	// Previous are the addresses that were current before this change.
+	Previous []net.IP
+}
+
+const defaultResolveInterval = 30 * time.Second
+
+// ResolveTargets periodically resolves hostnames to IP addresses and
+// publishes the current address set on the returned channel whenever it
+// changes. Resolution stops, and the channel is closed, when ctx is done.
+//
+// This mirrors NetBird's DNS routes feature: VIP targets can be hostnames as
+// well as literal IPs, re-resolved on an interval so that the config
+// renderer follows DNS changes instead of baking in a snapshot.
+func ResolveTargets(ctx context.Context, hostnames []string, opts ResolveTargetsOptions) (<-chan TargetAddresses, error) {
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultResolveInterval
+	}
+
+	out := make(chan TargetAddresses)
+	// lastResolved holds each host's most recent raw answer, used to decide
+	// whether a re-resolution changed anything. emitted holds the address
+	// set last published for the host, which under KeepPrevious is a
+	// superset of lastResolved and must be tracked separately: otherwise a
+	// single divergence would make every later tick's raw answer compare
+	// unequal to the retained superset and re-emit a no-op change forever.
+	lastResolved := make(map[string][]net.IP, len(hostnames))
+	emitted := make(map[string][]net.IP, len(hostnames))
+
+	resolveOnce := func() {
+		for _, host := range hostnames {
+			addrs, err := resolver.LookupIPAddr(ctx, host)
+			if err != nil || len(addrs) == 0 {
+				continue
+			}
+			resolved := make([]net.IP, 0, len(addrs))
+			for _, a := range addrs {
+				resolved = append(resolved, a.IP)
+			}
+
+			if sameAddresses(lastResolved[host], resolved) {
+				continue
+			}
+			lastResolved[host] = resolved
+
+			previous := emitted[host]
+			current := resolved
+			if opts.KeepPrevious {
+				current = mergeAddresses(previous, resolved)
+			}
+			if sameAddresses(previous, current) {
+				continue
+			}
+			emitted[host] = current
+
+			select {
+			case out <- TargetAddresses{Host: host, Current: current, Previous: previous}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	go func() {
+		defer close(out)
+		resolveOnce()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				resolveOnce()
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func sameAddresses(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func mergeAddresses(previous, resolved []net.IP) []net.IP {
+	merged := make([]net.IP, 0, len(previous)+len(resolved))
+	seen := make(map[string]bool, len(previous)+len(resolved))
+	for _, ip := range append(append([]net.IP{}, resolved...), previous...) {
+		key := ip.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, ip)
+	}
+	return merged
+}
+
+// addressesRoutingToHostnames is the hostname-aware counterpart to
+// AddressesRouting: it resolves hostnames once and routes each resolved IP
+// the same way a literal VIP would be routed.
+func addressesRoutingToHostnames(ctx context.Context, hostnames []string, af AddressFilter, resolver Resolver, getAddrs addrMapFunc, getRoutes routeMapFunc) ([]net.IP, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	var vips []net.IP
+	for _, host := range hostnames {
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			vips = append(vips, a.IP)
+		}
+	}
+
+	return addressesRoutingInternal(vips, af, getAddrs, getRoutes)
+}
+
+// AddressesRoutingToHostnames resolves the given hostnames and returns the
+// node addresses on the interface that routes to each resolved IP, the same
+// way AddressesRouting does for literal VIPs.
+func AddressesRoutingToHostnames(ctx context.Context, hostnames []string, af AddressFilter) ([]net.IP, error) {
+	return addressesRoutingToHostnames(ctx, hostnames, af, net.DefaultResolver, getAddrMap, getRouteMap)
+}
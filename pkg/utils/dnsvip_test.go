@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeResolver answers LookupIPAddr from a per-host queue of canned answers,
+// advancing to the next answer on each call so tests can simulate a DNS
+// change between resolutions.
+type fakeResolver struct {
+	mu      sync.Mutex
+	answers map[string][][]net.IPAddr
+}
+
+func (f *fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	queue := f.answers[host]
+	if len(queue) == 0 {
+		return nil, nil
+	}
+	next := queue[0]
+	if len(queue) > 1 {
+		f.answers[host] = queue[1:]
+	}
+	return next, nil
+}
+
+func ipAddrs(ips ...string) []net.IPAddr {
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs
+}
+
+var _ = Describe("ResolveTargets", func() {
+	It("publishes the initial resolution", func() {
+		resolver := &fakeResolver{answers: map[string][][]net.IPAddr{
+			"vip.example.com": {ipAddrs("10.0.0.5")},
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		changes, err := ResolveTargets(ctx, []string{"vip.example.com"}, ResolveTargetsOptions{
+			Resolver: resolver,
+			Interval: time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var change TargetAddresses
+		Eventually(changes).Should(Receive(&change))
+		Expect(change.Host).To(Equal("vip.example.com"))
+		Expect(change.Current).To(Equal([]net.IP{net.ParseIP("10.0.0.5")}))
+		Expect(change.Previous).To(BeEmpty())
+	})
+
+	It("tags each change with the hostname it came from", func() {
+		resolver := &fakeResolver{answers: map[string][][]net.IPAddr{
+			"vip-a.example.com": {ipAddrs("10.0.0.5")},
+			"vip-b.example.com": {ipAddrs("10.0.0.6")},
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		changes, err := ResolveTargets(ctx, []string{"vip-a.example.com", "vip-b.example.com"}, ResolveTargetsOptions{
+			Resolver: resolver,
+			Interval: time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		seen := map[string][]net.IP{}
+		for i := 0; i < 2; i++ {
+			var change TargetAddresses
+			Eventually(changes).Should(Receive(&change))
+			seen[change.Host] = change.Current
+		}
+		Expect(seen).To(HaveKeyWithValue("vip-a.example.com", []net.IP{net.ParseIP("10.0.0.5")}))
+		Expect(seen).To(HaveKeyWithValue("vip-b.example.com", []net.IP{net.ParseIP("10.0.0.6")}))
+	})
+
+	It("replaces addresses on change by default", func() {
+		resolver := &fakeResolver{answers: map[string][][]net.IPAddr{
+			"vip.example.com": {ipAddrs("10.0.0.5"), ipAddrs("10.0.0.6")},
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		changes, err := ResolveTargets(ctx, []string{"vip.example.com"}, ResolveTargetsOptions{
+			Resolver: resolver,
+			Interval: time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var first TargetAddresses
+		Eventually(changes).Should(Receive(&first))
+
+		var second TargetAddresses
+		Eventually(changes).Should(Receive(&second))
+		Expect(second.Current).To(Equal([]net.IP{net.ParseIP("10.0.0.6")}))
+		Expect(second.Previous).To(Equal([]net.IP{net.ParseIP("10.0.0.5")}))
+	})
+
+	It("keeps the previous address alongside the new one when KeepPrevious is set", func() {
+		resolver := &fakeResolver{answers: map[string][][]net.IPAddr{
+			"vip.example.com": {ipAddrs("fd00::5"), ipAddrs("fd00::6")},
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		changes, err := ResolveTargets(ctx, []string{"vip.example.com"}, ResolveTargetsOptions{
+			Resolver:     resolver,
+			Interval:     time.Millisecond,
+			KeepPrevious: true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var first TargetAddresses
+		Eventually(changes).Should(Receive(&first))
+
+		var second TargetAddresses
+		Eventually(changes).Should(Receive(&second))
+		Expect(second.Current).To(ConsistOf(net.ParseIP("fd00::6"), net.ParseIP("fd00::5")))
+
+		// The resolver keeps returning the same (now stale) answer on every
+		// later tick. Since the merged/retained set never again equals that
+		// raw answer, a naive comparison would re-emit this as a "change"
+		// forever; it must not.
+		Consistently(changes, 50*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("stops and closes the channel when the context is cancelled", func() {
+		resolver := &fakeResolver{answers: map[string][][]net.IPAddr{
+			"vip.example.com": {ipAddrs("10.0.0.5")},
+		}}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		changes, err := ResolveTargets(ctx, []string{"vip.example.com"}, ResolveTargetsOptions{
+			Resolver: resolver,
+			Interval: time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var first TargetAddresses
+		Eventually(changes).Should(Receive(&first))
+
+		cancel()
+		Eventually(changes).Should(BeClosed())
+	})
+})
+
+var _ = Describe("AddressesRoutingToHostnames", func() {
+	It("resolves hostnames and routes the result like a literal VIP", func() {
+		resolver := &fakeResolver{answers: map[string][][]net.IPAddr{
+			"vip.example.com": {ipAddrs("10.0.0.2")},
+		}}
+
+		addrs, err := addressesRoutingToHostnames(
+			context.Background(),
+			[]string{"vip.example.com"},
+			ValidNodeAddress,
+			resolver,
+			ipv4AddrMap,
+			ipv4RouteMap,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]net.IP{net.ParseIP("10.0.0.5")}))
+	})
+})
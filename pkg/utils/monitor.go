@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+// EventKind identifies what changed in a Monitor Event.
+type EventKind int
+
+const (
+	EventKindAddrAdded EventKind = iota
+	EventKindAddrRemoved
+	EventKindRouteAdded
+	EventKindRouteRemoved
+	EventKindLinkRemoved
+)
+
+// Event is a single incremental address or route change observed by a
+// Monitor.
+type Event struct {
+	Kind      EventKind
+	LinkIndex int
+	Addr      netlink.Addr
+	Route     netlink.Route
+}
+
+// Snapshot is a Monitor's view of addresses and routes, keyed by link index
+// rather than netlink.Link so that it stays comparable and valid across
+// interface churn.
+type Snapshot struct {
+	Addrs  map[int][]netlink.Addr
+	Routes map[int][]netlink.Route
+}
+
+func newSnapshot() Snapshot {
+	return Snapshot{Addrs: map[int][]netlink.Addr{}, Routes: map[int][]netlink.Route{}}
+}
+
+func (s Snapshot) clone() Snapshot {
+	out := newSnapshot()
+	for idx, addrs := range s.Addrs {
+		out.Addrs[idx] = append([]netlink.Addr(nil), addrs...)
+	}
+	for idx, routes := range s.Routes {
+		out.Routes[idx] = append([]netlink.Route(nil), routes...)
+	}
+	return out
+}
+
+// applyEvent folds a single Event into state and returns the updated
+// Snapshot. It is the Monitor's reducer: it has no dependency on netlink
+// subscriptions, so tests exercise it by replaying fixture address/route
+// maps as a stream of Events.
+func applyEvent(state Snapshot, ev Event) Snapshot {
+	switch ev.Kind {
+	case EventKindAddrAdded:
+		if !containsAddr(state.Addrs[ev.LinkIndex], ev.Addr) {
+			state.Addrs[ev.LinkIndex] = append(state.Addrs[ev.LinkIndex], ev.Addr)
+		}
+	case EventKindAddrRemoved:
+		state.Addrs[ev.LinkIndex] = removeAddr(state.Addrs[ev.LinkIndex], ev.Addr)
+	case EventKindRouteAdded:
+		if !containsRoute(state.Routes[ev.LinkIndex], ev.Route) {
+			state.Routes[ev.LinkIndex] = append(state.Routes[ev.LinkIndex], ev.Route)
+		}
+	case EventKindRouteRemoved:
+		state.Routes[ev.LinkIndex] = removeRoute(state.Routes[ev.LinkIndex], ev.Route)
+	case EventKindLinkRemoved:
+		delete(state.Addrs, ev.LinkIndex)
+		delete(state.Routes, ev.LinkIndex)
+	}
+	return state
+}
+
+func containsAddr(addrs []netlink.Addr, target netlink.Addr) bool {
+	for _, a := range addrs {
+		if a.Equal(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRoute(routes []netlink.Route, target netlink.Route) bool {
+	for _, r := range routes {
+		if r.Equal(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeAddr(addrs []netlink.Addr, target netlink.Addr) []netlink.Addr {
+	out := addrs[:0]
+	for _, a := range addrs {
+		if a.Equal(target) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func removeRoute(routes []netlink.Route, target netlink.Route) []netlink.Route {
+	out := routes[:0]
+	for _, r := range routes {
+		if r.Equal(target) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Monitor maintains a cached view of addresses and routes updated
+// incrementally from netlink change notifications, instead of callers
+// polling AddressesRouting/AddressesDefault on an interval.
+type Monitor struct {
+	mu    sync.RWMutex
+	state Snapshot
+
+	changes   chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMonitor starts watching RTMGRP_LINK, RTMGRP_IPV4_IFADDR,
+// RTMGRP_IPV6_IFADDR, RTMGRP_IPV4_ROUTE and RTMGRP_IPV6_ROUTE for incremental
+// updates, and seeds the Monitor's snapshot from the same subscriptions via
+// ListExisting rather than a separate dump: a dump-then-subscribe sequence
+// has a gap in which changes land nowhere. Any update replayed by
+// ListExisting that an update already delivered (or vice versa) is
+// reconciled by applyEvent's add-side dedup.
+func NewMonitor(af AddressFilter, rf RouteFilter) (*Monitor, error) {
+	m := &Monitor{
+		state:   newSnapshot(),
+		changes: make(chan Event, 64),
+		done:    make(chan struct{}),
+	}
+
+	addrUpdates := make(chan netlink.AddrUpdate, 64)
+	if err := netlink.AddrSubscribeWithOptions(addrUpdates, m.done, netlink.AddrSubscribeOptions{ListExisting: true}); err != nil {
+		m.Close()
+		return nil, fmt.Errorf("failed to subscribe to address updates: %w", err)
+	}
+	routeUpdates := make(chan netlink.RouteUpdate, 64)
+	if err := netlink.RouteSubscribeWithOptions(routeUpdates, m.done, netlink.RouteSubscribeOptions{ListExisting: true}); err != nil {
+		m.Close()
+		return nil, fmt.Errorf("failed to subscribe to route updates: %w", err)
+	}
+	linkUpdates := make(chan netlink.LinkUpdate, 64)
+	if err := netlink.LinkSubscribeWithOptions(linkUpdates, m.done, netlink.LinkSubscribeOptions{ListExisting: true}); err != nil {
+		m.Close()
+		return nil, fmt.Errorf("failed to subscribe to link updates: %w", err)
+	}
+
+	go m.run(addrUpdates, routeUpdates, linkUpdates, af, rf)
+	return m, nil
+}
+
+func (m *Monitor) run(addrUpdates chan netlink.AddrUpdate, routeUpdates chan netlink.RouteUpdate, linkUpdates chan netlink.LinkUpdate, af AddressFilter, rf RouteFilter) {
+	for {
+		select {
+		case <-m.done:
+			return
+		case update, ok := <-addrUpdates:
+			if !ok {
+				return
+			}
+			addr := netlink.Addr{
+				IPNet:       &update.LinkAddress,
+				Flags:       update.Flags,
+				Scope:       update.Scope,
+				PreferedLft: update.PreferedLft,
+				ValidLft:    update.ValidLft,
+			}
+			// af gates additions only. DELADDR, and a NEWADDR that af now
+			// rejects (e.g. the kernel deprecated it, PreferedLft dropping
+			// to 0), must still be processed as a removal so a
+			// previously-accepted address doesn't linger in the snapshot
+			// forever.
+			kind := EventKindAddrRemoved
+			if update.NewAddr && (af == nil || af(addr)) {
+				kind = EventKindAddrAdded
+			}
+			m.dispatch(Event{Kind: kind, LinkIndex: update.LinkIndex, Addr: addr})
+		case update, ok := <-routeUpdates:
+			if !ok {
+				return
+			}
+			// Same reasoning as the address case: rf gates additions only,
+			// so a route that stops matching rf (or a genuine RTM_DELROUTE)
+			// is always processed as a removal.
+			kind := EventKindRouteRemoved
+			if update.Type == syscall.RTM_NEWROUTE && (rf == nil || rf(update.Route)) {
+				kind = EventKindRouteAdded
+			}
+			m.dispatch(Event{Kind: kind, LinkIndex: update.Route.LinkIndex, Route: update.Route})
+		case update, ok := <-linkUpdates:
+			if !ok {
+				return
+			}
+			if update.Header.Type == syscall.RTM_DELLINK {
+				m.dispatch(Event{Kind: EventKindLinkRemoved, LinkIndex: int(update.Index)})
+			}
+		}
+	}
+}
+
+func (m *Monitor) dispatch(ev Event) {
+	m.mu.Lock()
+	m.state = applyEvent(m.state, ev)
+	m.mu.Unlock()
+
+	// Snapshot() is the source of truth and is already up to date at this
+	// point; publishing to Changes() is best-effort. A caller that only
+	// calls Snapshot() and never drains Changes() must not stall this
+	// goroutine and freeze the snapshot once the buffer fills.
+	select {
+	case m.changes <- ev:
+	default:
+	}
+}
+
+// Snapshot returns the Monitor's current address/route view.
+func (m *Monitor) Snapshot() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.clone()
+}
+
+// Changes returns the channel on which incremental Events are published.
+func (m *Monitor) Changes() <-chan Event {
+	return m.changes
+}
+
+// Close stops watching for updates and releases the Monitor's subscriptions.
+func (m *Monitor) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vishvananda/netlink"
+)
+
+// replayAsEvents turns the existing fixture-based addrMapFunc/routeMapFunc
+// pairs into a stream of EventKindAddrAdded/EventKindRouteAdded Events, so
+// the Monitor reducer can be tested without a real netlink subscription.
+func replayAsEvents(addrs map[netlink.Link][]netlink.Addr, routes map[int][]netlink.Route) []Event {
+	var events []Event
+	for link, linkAddrs := range addrs {
+		for _, a := range linkAddrs {
+			events = append(events, Event{Kind: EventKindAddrAdded, LinkIndex: link.Attrs().Index, Addr: a})
+		}
+	}
+	for idx, linkRoutes := range routes {
+		for _, r := range linkRoutes {
+			events = append(events, Event{Kind: EventKindRouteAdded, LinkIndex: idx, Route: r})
+		}
+	}
+	return events
+}
+
+var _ = Describe("applyEvent", func() {
+	It("reconstructs a snapshot equivalent to the fixture maps", func() {
+		addrs, err := ipv4AddrMap(nil)
+		Expect(err).NotTo(HaveOccurred())
+		routes, err := ipv4RouteMap(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		state := newSnapshot()
+		for _, ev := range replayAsEvents(addrs, routes) {
+			state = applyEvent(state, ev)
+		}
+
+		Expect(state.Addrs[eth0.Attrs().Index]).To(ContainElement(WithTransform(
+			func(a netlink.Addr) string { return a.IPNet.String() }, Equal("10.0.0.5/24"))))
+		Expect(state.Routes[eth1.Attrs().Index]).To(HaveLen(1))
+	})
+
+	It("removes an address on EventKindAddrRemoved", func() {
+		addr, err := netlink.ParseAddr("10.0.0.5/24")
+		Expect(err).NotTo(HaveOccurred())
+
+		state := newSnapshot()
+		state = applyEvent(state, Event{Kind: EventKindAddrAdded, LinkIndex: eth0.Attrs().Index, Addr: *addr})
+		Expect(state.Addrs[eth0.Attrs().Index]).To(HaveLen(1))
+
+		state = applyEvent(state, Event{Kind: EventKindAddrRemoved, LinkIndex: eth0.Attrs().Index, Addr: *addr})
+		Expect(state.Addrs[eth0.Attrs().Index]).To(BeEmpty())
+	})
+
+	It("drops all addresses and routes for a link on EventKindLinkRemoved", func() {
+		addrs, err := ipv4AddrMap(nil)
+		Expect(err).NotTo(HaveOccurred())
+		routes, err := ipv4RouteMap(nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		state := newSnapshot()
+		for _, ev := range replayAsEvents(addrs, routes) {
+			state = applyEvent(state, ev)
+		}
+		Expect(state.Addrs[eth0.Attrs().Index]).NotTo(BeEmpty())
+
+		state = applyEvent(state, Event{Kind: EventKindLinkRemoved, LinkIndex: eth0.Attrs().Index})
+		Expect(state.Addrs[eth0.Attrs().Index]).To(BeEmpty())
+		Expect(state.Routes[eth0.Attrs().Index]).To(BeEmpty())
+	})
+
+	It("does not duplicate an address re-added on a repeated NEWADDR", func() {
+		addr, err := netlink.ParseAddr("10.0.0.5/24")
+		Expect(err).NotTo(HaveOccurred())
+
+		state := newSnapshot()
+		ev := Event{Kind: EventKindAddrAdded, LinkIndex: eth0.Attrs().Index, Addr: *addr}
+		state = applyEvent(state, ev)
+		state = applyEvent(state, ev)
+		Expect(state.Addrs[eth0.Attrs().Index]).To(HaveLen(1))
+	})
+
+	It("does not duplicate a route re-added on a repeated NEWROUTE", func() {
+		_, dst, err := net.ParseCIDR("10.0.0.0/24")
+		Expect(err).NotTo(HaveOccurred())
+		route := netlink.Route{LinkIndex: eth0.Attrs().Index, Dst: dst}
+
+		state := newSnapshot()
+		ev := Event{Kind: EventKindRouteAdded, LinkIndex: eth0.Attrs().Index, Route: route}
+		state = applyEvent(state, ev)
+		state = applyEvent(state, ev)
+		Expect(state.Routes[eth0.Attrs().Index]).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Monitor.run", func() {
+	It("tracks an address added after seeding when it passes ValidNodeAddress", func() {
+		m := &Monitor{
+			state:   newSnapshot(),
+			changes: make(chan Event, 4),
+			done:    make(chan struct{}),
+		}
+		addrUpdates := make(chan netlink.AddrUpdate)
+		routeUpdates := make(chan netlink.RouteUpdate)
+		linkUpdates := make(chan netlink.LinkUpdate)
+		go m.run(addrUpdates, routeUpdates, linkUpdates, ValidNodeAddress, nil)
+		defer m.Close()
+
+		_, ipnet, err := net.ParseCIDR("10.0.0.100/24")
+		Expect(err).NotTo(HaveOccurred())
+		ipnet.IP = net.ParseIP("10.0.0.100")
+
+		addrUpdates <- netlink.AddrUpdate{
+			LinkIndex:   eth0.Attrs().Index,
+			LinkAddress: *ipnet,
+			NewAddr:     true,
+			PreferedLft: 3600,
+			ValidLft:    3600,
+		}
+
+		var ev Event
+		Eventually(m.Changes()).Should(Receive(&ev))
+		Expect(ev.Kind).To(Equal(EventKindAddrAdded))
+		Expect(m.Snapshot().Addrs[eth0.Attrs().Index]).To(ContainElement(WithTransform(
+			func(a netlink.Addr) string { return a.IP.String() }, Equal("10.0.0.100"))))
+	})
+
+	It("removes an address once the kernel deprecates it, instead of leaving a stale entry", func() {
+		m := &Monitor{
+			state:   newSnapshot(),
+			changes: make(chan Event, 4),
+			done:    make(chan struct{}),
+		}
+		addrUpdates := make(chan netlink.AddrUpdate)
+		routeUpdates := make(chan netlink.RouteUpdate)
+		linkUpdates := make(chan netlink.LinkUpdate)
+		go m.run(addrUpdates, routeUpdates, linkUpdates, ValidNodeAddress, nil)
+		defer m.Close()
+
+		_, ipnet, err := net.ParseCIDR("10.0.0.100/24")
+		Expect(err).NotTo(HaveOccurred())
+		ipnet.IP = net.ParseIP("10.0.0.100")
+
+		addrUpdates <- netlink.AddrUpdate{
+			LinkIndex: eth0.Attrs().Index, LinkAddress: *ipnet, NewAddr: true,
+			PreferedLft: 3600, ValidLft: 3600,
+		}
+		var added Event
+		Eventually(m.Changes()).Should(Receive(&added))
+		Expect(added.Kind).To(Equal(EventKindAddrAdded))
+
+		// The kernel redelivers a NEWADDR for the same address once it
+		// deprecates it (PreferedLft drops to 0); ValidNodeAddress now
+		// rejects it, but it must still be removed, not ignored.
+		addrUpdates <- netlink.AddrUpdate{
+			LinkIndex: eth0.Attrs().Index, LinkAddress: *ipnet, NewAddr: true,
+			PreferedLft: 0, ValidLft: 3600,
+		}
+		var deprecated Event
+		Eventually(m.Changes()).Should(Receive(&deprecated))
+		Expect(deprecated.Kind).To(Equal(EventKindAddrRemoved))
+		Expect(m.Snapshot().Addrs[eth0.Attrs().Index]).To(BeEmpty())
+	})
+
+	It("processes a DELADDR for an address that af would reject", func() {
+		m := &Monitor{
+			state:   newSnapshot(),
+			changes: make(chan Event, 4),
+			done:    make(chan struct{}),
+		}
+		addrUpdates := make(chan netlink.AddrUpdate)
+		routeUpdates := make(chan netlink.RouteUpdate)
+		linkUpdates := make(chan netlink.LinkUpdate)
+		go m.run(addrUpdates, routeUpdates, linkUpdates, ValidNodeAddress, nil)
+		defer m.Close()
+
+		// Seed the state directly: loopback addresses are never accepted by
+		// af on add, but a real kernel may still emit a DELADDR for one.
+		loAddr, err := netlink.ParseAddr("127.0.0.2/8")
+		Expect(err).NotTo(HaveOccurred())
+		m.mu.Lock()
+		m.state.Addrs[lo.Attrs().Index] = []netlink.Addr{*loAddr}
+		m.mu.Unlock()
+
+		addrUpdates <- netlink.AddrUpdate{
+			LinkIndex: lo.Attrs().Index, LinkAddress: *loAddr.IPNet, NewAddr: false,
+		}
+		var ev Event
+		Eventually(m.Changes()).Should(Receive(&ev))
+		Expect(ev.Kind).To(Equal(EventKindAddrRemoved))
+		Expect(m.Snapshot().Addrs[lo.Attrs().Index]).To(BeEmpty())
+	})
+
+	It("keeps applying updates to the snapshot once Changes() stops being drained", func() {
+		m := &Monitor{
+			state:   newSnapshot(),
+			changes: make(chan Event, 1),
+			done:    make(chan struct{}),
+		}
+		addrUpdates := make(chan netlink.AddrUpdate)
+		routeUpdates := make(chan netlink.RouteUpdate)
+		linkUpdates := make(chan netlink.LinkUpdate)
+		go m.run(addrUpdates, routeUpdates, linkUpdates, ValidNodeAddress, nil)
+		defer m.Close()
+
+		for i := 0; i < 5; i++ {
+			_, ipnet, err := net.ParseCIDR(fmt.Sprintf("10.0.1.%d/24", i+1))
+			Expect(err).NotTo(HaveOccurred())
+			ipnet.IP = net.ParseIP(fmt.Sprintf("10.0.1.%d", i+1))
+			addrUpdates <- netlink.AddrUpdate{
+				LinkIndex: eth0.Attrs().Index, LinkAddress: *ipnet, NewAddr: true,
+				PreferedLft: 3600, ValidLft: 3600,
+			}
+		}
+
+		Eventually(func() int {
+			return len(m.Snapshot().Addrs[eth0.Attrs().Index])
+		}).Should(Equal(5))
+	})
+})
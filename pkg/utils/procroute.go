@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+)
+
+// envForceProcRoute, when set to any non-empty value, makes AddressesDefault
+// discover the default-route interface by parsing /proc/net/route and
+// /proc/net/ipv6_route instead of querying netlink. This mirrors the
+// fallback Kubernetes' apimachinery net/interface package uses, and lets
+// runtimecfg run on hosts where netlink is unavailable or restricted (e.g.
+// unprivileged containers without NET_ADMIN).
+const envForceProcRoute = "RUNTIMECFG_FORCE_PROC_ROUTE"
+
+const (
+	procNetRouteFile     = "/proc/net/route"
+	procNetIPv6RouteFile = "/proc/net/ipv6_route"
+
+	rtfUp      = 0x0001
+	rtfGateway = 0x0002
+)
+
+func selectDefaultAddressFactory() func(AddressFilter) ([]net.IP, error) {
+	if os.Getenv(envForceProcRoute) != "" {
+		return addressesDefaultProc
+	}
+	return func(af AddressFilter) ([]net.IP, error) {
+		return addressesDefaultInternal(af, getAddrMap, getRouteMap)
+	}
+}
+
+// addressesDefaultProc discovers the default-route interface without
+// netlink, then returns its node addresses the same way addressesDefaultInternal
+// does for the netlink path.
+func addressesDefaultProc(af AddressFilter) ([]net.IP, error) {
+	ifaceName, err := defaultRouteInterfaceFromProc()
+	if err != nil {
+		return nil, err
+	}
+
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up default route interface %s: %w", ifaceName, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses for %s: %w", ifaceName, err)
+	}
+
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: iface.Index, Name: iface.Name}}
+	var linkAddrs []netlink.Addr
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		// /proc/net/route carries no lifetime information, so addresses
+		// found this way are treated as preferred forever.
+		nlAddr := netlink.Addr{IPNet: ipnet, PreferedLft: math.MaxUint32}
+		if af != nil && !af(nlAddr) {
+			continue
+		}
+		linkAddrs = append(linkAddrs, nlAddr)
+	}
+
+	return addressesForLink(linkAddrs, true), nil
+}
+
+// defaultRouteInterfaceFromProc returns the name of the interface carrying
+// the default route, preferring an IPv4 default route over an IPv6 one.
+func defaultRouteInterfaceFromProc() (string, error) {
+	v4File, v4Err := os.Open(procNetRouteFile)
+	if v4Err == nil {
+		defer v4File.Close()
+		if iface, err := parseProcNetRoute(v4File); err == nil {
+			return iface, nil
+		}
+	}
+
+	v6File, v6Err := os.Open(procNetIPv6RouteFile)
+	if v6Err == nil {
+		defer v6File.Close()
+		if iface, err := parseProcNetIPv6Route(v6File); err == nil {
+			return iface, nil
+		}
+	}
+
+	return "", fmt.Errorf("no default route found in %s or %s", procNetRouteFile, procNetIPv6RouteFile)
+}
+
+// parseProcNetRoute parses the contents of /proc/net/route and returns the
+// name of the interface carrying the default (0.0.0.0/0) route with the
+// lowest metric, independent of the line's position in the file.
+func parseProcNetRoute(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	found := false
+	var bestIface string
+	var bestMetric int
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if lineNum == 1 {
+			continue // header
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+
+		dest, err := strconv.ParseUint(fields[1], 16, 32)
+		if err != nil || dest != 0 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 16)
+		if err != nil || flags&(rtfUp|rtfGateway) != (rtfUp|rtfGateway) {
+			continue
+		}
+		metric, err := strconv.Atoi(fields[6])
+		if err != nil {
+			continue
+		}
+
+		if !found || metric < bestMetric {
+			bestIface = fields[0]
+			bestMetric = metric
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no default route found in %s", procNetRouteFile)
+	}
+	return bestIface, nil
+}
+
+// parseProcNetIPv6Route parses the contents of /proc/net/ipv6_route and
+// returns the name of the interface carrying the default (::/0) route with
+// the lowest metric, independent of the line's position in the file.
+func parseProcNetIPv6Route(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	found := false
+	var bestIface string
+	var bestMetric uint64
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		destLen := fields[1]
+		if destLen != "00" || strings.Trim(fields[0], "0") != "" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[8], 16, 32)
+		if err != nil || flags&(rtfUp|rtfGateway) != (rtfUp|rtfGateway) {
+			continue
+		}
+		metric, err := strconv.ParseUint(fields[5], 16, 64)
+		if err != nil {
+			continue
+		}
+
+		if !found || metric < bestMetric {
+			bestIface = fields[9]
+			bestMetric = metric
+			found = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no default route found in %s", procNetIPv6RouteFile)
+	}
+	return bestIface, nil
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const procNetRouteHeader = "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT"
+
+// gatewayFirstFixture has its default route on the first data line.
+const gatewayFirstFixture = procNetRouteHeader + "\n" +
+	"gatewayfirst\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+	"eth1\t0000FEA9\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n" +
+	"eth2\t00000A0A\t00000000\t0001\t0\t0\t0\t000000FF\t0\t0\t0\n"
+
+// gatewayLastFixture has its default route on the last data line.
+const gatewayLastFixture = procNetRouteHeader + "\n" +
+	"eth1\t0000FEA9\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n" +
+	"eth2\t00000A0A\t00000000\t0001\t0\t0\t0\t000000FF\t0\t0\t0\n" +
+	"gatewaylast\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n"
+
+// gatewayMiddleFixture has its default route on a middle data line.
+const gatewayMiddleFixture = procNetRouteHeader + "\n" +
+	"eth1\t0000FEA9\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n" +
+	"gatewaymiddle\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+	"eth2\t00000A0A\t00000000\t0001\t0\t0\t0\t000000FF\t0\t0\t0\n"
+
+// gatewayLowestMetricFixture has two default routes; the one with the lower
+// metric should win regardless of file order.
+const gatewayLowestMetricFixture = procNetRouteHeader + "\n" +
+	"highmetric\t00000000\t0101A8C0\t0003\t0\t0\t200\t00000000\t0\t0\t0\n" +
+	"lowmetric\t00000000\t0102A8C0\t0003\t0\t0\t50\t00000000\t0\t0\t0\n"
+
+var _ = Describe("parseProcNetRoute", func() {
+	It("finds the default route when it's the first line", func() {
+		iface, err := parseProcNetRoute(strings.NewReader(gatewayFirstFixture))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(iface).To(Equal("gatewayfirst"))
+	})
+
+	It("finds the default route when it's the last line", func() {
+		iface, err := parseProcNetRoute(strings.NewReader(gatewayLastFixture))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(iface).To(Equal("gatewaylast"))
+	})
+
+	It("finds the default route when it's a middle line", func() {
+		iface, err := parseProcNetRoute(strings.NewReader(gatewayMiddleFixture))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(iface).To(Equal("gatewaymiddle"))
+	})
+
+	It("picks the default route with the lowest metric on ties", func() {
+		iface, err := parseProcNetRoute(strings.NewReader(gatewayLowestMetricFixture))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(iface).To(Equal("lowmetric"))
+	})
+
+	It("errors when no default route is present", func() {
+		_, err := parseProcNetRoute(strings.NewReader(procNetRouteHeader + "\n" +
+			"eth1\t0000FEA9\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+// IPv6 fixture: dest destlen src srclen nexthop metric refcnt use flags devname.
+const gatewayV6Fixture = "00000000000000000000000000000000 00 00000000000000000000000000000000 00 " +
+	"fe800000000000000000000000000001 00000064 00000000 00000000 00000003 gatewayv6\n"
+
+var _ = Describe("parseProcNetIPv6Route", func() {
+	It("finds the IPv6 default route", func() {
+		iface, err := parseProcNetIPv6Route(strings.NewReader(gatewayV6Fixture))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(iface).To(Equal("gatewayv6"))
+	})
+
+	It("errors when no IPv6 default route is present", func() {
+		nonDefault := "fd00000000000000000000000000000000 40 00000000000000000000000000000000 00 " +
+			"00000000000000000000000000000000 00000000 00000000 00000000 00000001 eth0\n"
+		_, err := parseProcNetIPv6Route(strings.NewReader(nonDefault))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"net"
+	"sort"
+
+	"github.com/vishvananda/netlink"
+)
+
+// UplinkAddresses holds the AddressesLikelyUplink result, one slice per
+// family, each ordered from most to least likely.
+type UplinkAddresses struct {
+	V4 []net.IP
+	V6 []net.IP
+}
+
+type scoredAddr struct {
+	ip    net.IP
+	score int
+}
+
+type sourceProbeFunc func() net.IP
+
+// AddressesLikelyUplink picks the interface(s) most likely to face the
+// upstream router, for use as a fallback node address when no VIP-routed
+// match exists and no default route is present (e.g. a misconfigured
+// bootstrap host). It considers only running, non-loopback interfaces; down
+// and loopback interfaces are skipped entirely. Among the rest it prefers
+// addresses whose subnet has room for a gateway or that match
+// the source address the kernel picks for an off-link UDP connect.
+//
+// This mirrors Tailscale's LikelyHomeRouterIP heuristic in net/interfaces.
+func AddressesLikelyUplink(af AddressFilter) (UplinkAddresses, error) {
+	return addressesLikelyUplinkInternal(af, getAddrMap, probeUplinkV4, probeUplinkV6)
+}
+
+func addressesLikelyUplinkInternal(af AddressFilter, getAddrs addrMapFunc, probeV4, probeV6 sourceProbeFunc) (UplinkAddresses, error) {
+	addrMap, err := getAddrs(af)
+	if err != nil {
+		return UplinkAddresses{}, err
+	}
+
+	probedV4 := probeV4()
+	probedV6 := probeV6()
+
+	var v4, v6 []scoredAddr
+	for _, link := range sortedLinks(addrMap) {
+		flags := link.Attrs().Flags
+		if flags&net.FlagLoopback != 0 {
+			continue
+		}
+		if flags&net.FlagUp == 0 || flags&net.FlagRunning == 0 {
+			continue
+		}
+
+		for _, addr := range addrMap[link] {
+			score := scoreUplinkAddress(addr, probedV4, probedV6)
+			if addr.IP.To4() != nil {
+				v4 = append(v4, scoredAddr{addr.IP, score})
+			} else {
+				v6 = append(v6, scoredAddr{addr.IP, score})
+			}
+		}
+	}
+
+	sortByScoreDesc(v4)
+	sortByScoreDesc(v6)
+	return UplinkAddresses{V4: toIPs(v4), V6: toIPs(v6)}, nil
+}
+
+// scoreUplinkAddress ranks addr as a plausible uplink address: a match with
+// the kernel's own off-link route choice scores highest, followed by
+// addresses in a subnet with room for a gateway.
+func scoreUplinkAddress(addr netlink.Addr, probedV4, probedV6 net.IP) int {
+	score := 0
+	if (probedV4 != nil && addr.IP.Equal(probedV4)) || (probedV6 != nil && addr.IP.Equal(probedV6)) {
+		score += 2
+	}
+	if addr.IPNet != nil && subnetHasPlausibleGateway(addr.IPNet, addr.IP) {
+		score++
+	}
+	return score
+}
+
+// subnetHasPlausibleGateway reports whether n's subnet has room for a
+// distinct gateway address at the conventional .1/::1 offset.
+func subnetHasPlausibleGateway(n *net.IPNet, self net.IP) bool {
+	ones, bits := n.Mask.Size()
+	if bits-ones < 2 {
+		return false
+	}
+	candidate := n.IP.Mask(n.Mask)
+	if candidate == nil {
+		return false
+	}
+	candidate[len(candidate)-1] |= 1
+	return !candidate.Equal(self)
+}
+
+// probeUplinkV4 and probeUplinkV6 discover the local address the kernel
+// would pick to reach an off-link destination, via the "UDP connect trick":
+// net.Dial("udp", ...) never sends a packet but makes the kernel resolve a
+// route and bind a local address for it, which is the interface facing the
+// router when one exists.
+func probeUplinkV4() net.IP { return sourceIPFor("udp4", "203.0.113.1:53") }
+func probeUplinkV6() net.IP { return sourceIPFor("udp6", "[2001:db8::1]:53") }
+
+func sourceIPFor(network, raddr string) net.IP {
+	conn, err := net.Dial(network, raddr)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	udpAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	return udpAddr.IP
+}
+
+func sortByScoreDesc(addrs []scoredAddr) {
+	sort.SliceStable(addrs, func(i, j int) bool {
+		return addrs[i].score > addrs[j].score
+	})
+}
+
+func toIPs(addrs []scoredAddr) []net.IP {
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.ip)
+	}
+	return ips
+}
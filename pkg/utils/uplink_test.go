@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vishvananda/netlink"
+)
+
+var down0 = &netlink.Device{
+	LinkAttrs: netlink.LinkAttrs{Index: 10, Name: "down0", Flags: 0},
+}
+var loopback0 = &netlink.Device{
+	LinkAttrs: netlink.LinkAttrs{Index: 11, Name: "loopback0", Flags: net.FlagUp | net.FlagRunning | net.FlagLoopback},
+}
+var ipsixonly0 = &netlink.Device{
+	LinkAttrs: netlink.LinkAttrs{Index: 12, Name: "ipsixonly0", Flags: net.FlagUp | net.FlagRunning},
+}
+var fake0 = &netlink.Device{
+	LinkAttrs: netlink.LinkAttrs{Index: 13, Name: "fake0", Flags: net.FlagUp | net.FlagRunning},
+}
+var noGateway0 = &netlink.Device{
+	LinkAttrs: netlink.LinkAttrs{Index: 14, Name: "noGateway0", Flags: net.FlagUp | net.FlagRunning},
+}
+
+func uplinkAddrMap(af AddressFilter) (map[netlink.Link][]netlink.Addr, error) {
+	addrs := make(map[netlink.Link][]netlink.Addr)
+	maybeAddAddress(addrs, af, down0, "172.16.0.2/24", false)
+	maybeAddAddress(addrs, af, loopback0, "127.0.0.1/8", false)
+	maybeAddAddress(addrs, af, ipsixonly0, "fd00::2/64", false)
+	maybeAddAddress(addrs, af, fake0, "192.168.1.50/24", false)
+	// noGateway0's address *is* the conventional gateway address itself, so
+	// there's no room left in the subnet for a distinct router.
+	maybeAddAddress(addrs, af, noGateway0, "10.10.10.1/24", false)
+	return addrs, nil
+}
+
+func noProbe() net.IP { return nil }
+
+var _ = Describe("AddressesLikelyUplink", func() {
+	It("ignores down and loopback interfaces", func() {
+		result, err := addressesLikelyUplinkInternal(ValidNodeAddress, uplinkAddrMap, noProbe, noProbe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.V4).NotTo(ContainElement(net.ParseIP("172.16.0.2")))
+		Expect(result.V4).NotTo(ContainElement(net.ParseIP("127.0.0.1")))
+	})
+
+	It("returns v6 candidates separately from v4", func() {
+		result, err := addressesLikelyUplinkInternal(ValidNodeAddress, uplinkAddrMap, noProbe, noProbe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.V6).To(Equal([]net.IP{net.ParseIP("fd00::2")}))
+	})
+
+	It("ranks a subnet with room for a gateway above one without", func() {
+		result, err := addressesLikelyUplinkInternal(ValidNodeAddress, uplinkAddrMap, noProbe, noProbe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.V4).To(Equal([]net.IP{net.ParseIP("192.168.1.50"), net.ParseIP("10.10.10.1")}))
+	})
+
+	It("ranks a probed UDP-connect source address highest", func() {
+		probeV4 := func() net.IP { return net.ParseIP("10.10.10.1") }
+		result, err := addressesLikelyUplinkInternal(ValidNodeAddress, uplinkAddrMap, probeV4, noProbe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.V4).To(Equal([]net.IP{net.ParseIP("10.10.10.1"), net.ParseIP("192.168.1.50")}))
+	})
+})